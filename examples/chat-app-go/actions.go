@@ -0,0 +1,49 @@
+package main
+
+// connAction is a unit of work handed to a Connection's own actionLoop so
+// that state affecting that connection is only ever mutated by one
+// goroutine: the connection's own. Broadcasters and command handlers send
+// actions instead of touching conn.send directly. The one exception to the
+// ws side is /kick: kickUser writes its close frame to the target's conn.ws
+// and closes it from the command's goroutine.
+type connAction interface {
+	isConnAction()
+}
+
+type sendMessageAction struct{ data []byte }
+
+type joinRoomAction struct{ roomID string }
+
+type leaveRoomAction struct{}
+
+type closeAction struct{}
+
+func (sendMessageAction) isConnAction() {}
+func (joinRoomAction) isConnAction()    {}
+func (leaveRoomAction) isConnAction()   {}
+func (closeAction) isConnAction()       {}
+
+// actionLoop is the single goroutine that owns this connection: every
+// outbound write and every room change for this connection flows through
+// here, so handleBroadcast/broadcastToRoom never need to lock around
+// conn.send or race readPump's unregister path to close it.
+func (c *Connection) actionLoop() {
+	for act := range c.actions.Out() {
+		switch a := act.(type) {
+		case sendMessageAction:
+			c.send.Send(a.data)
+		case joinRoomAction:
+			if err := c.server.joinRoom(c.userID, a.roomID); err != nil {
+				c.server.sendToUser(c.userID, ErrorResponse{Type: MessageTypeError, Message: err.Error()})
+			}
+		case leaveRoomAction:
+			if err := c.server.joinRoom(c.userID, "general"); err != nil {
+				c.server.sendToUser(c.userID, ErrorResponse{Type: MessageTypeError, Message: err.Error()})
+			}
+		case closeAction:
+			c.send.Close()
+			c.actions.Close()
+			return
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// Package bridge mirrors PAMPA rooms to and from external chat systems
+// (webhooks, IRC, ...), matterbridge-style. It defines its own Message
+// type rather than importing the main package's ChatMessage so that
+// individual bridge implementations stay decoupled from the chat server.
+package bridge
+
+import (
+	"context"
+	"time"
+)
+
+// Message is the protocol-agnostic shape a Bridge sends and receives. The
+// host translates to/from its own ChatMessage at the boundary.
+type Message struct {
+	ID        string
+	Username  string
+	Content   string
+	RoomID    string
+	Timestamp time.Time
+}
+
+// Bridge mirrors messages between a local room and one external system.
+type Bridge interface {
+	// Start connects the bridge and delivers inbound messages on Receive
+	// until ctx is cancelled or a fatal error occurs.
+	Start(ctx context.Context) error
+	// Send relays a local message to the external system.
+	Send(msg Message) error
+	// Receive yields messages the external system delivered to the bridge.
+	Receive() <-chan Message
+}
+
+// Registry maps local room IDs to the bridges mirroring them.
+type Registry struct {
+	byRoom map[string][]Bridge
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{byRoom: make(map[string][]Bridge)}
+}
+
+// Register attaches b to roomID. A room may have more than one bridge.
+func (r *Registry) Register(roomID string, b Bridge) {
+	r.byRoom[roomID] = append(r.byRoom[roomID], b)
+}
+
+// For returns the bridges mirroring roomID, if any.
+func (r *Registry) For(roomID string) []Bridge {
+	return r.byRoom[roomID]
+}
+
+// All returns every registered bridge exactly once, e.g. so the caller can
+// Start them all at boot.
+func (r *Registry) All() []Bridge {
+	seen := make(map[Bridge]bool)
+	var all []Bridge
+	for _, bridges := range r.byRoom {
+		for _, b := range bridges {
+			if !seen[b] {
+				seen[b] = true
+				all = append(all, b)
+			}
+		}
+	}
+	return all
+}
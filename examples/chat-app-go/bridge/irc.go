@@ -0,0 +1,82 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	irc "github.com/thoj/go-ircevent"
+)
+
+// IRCBridge mirrors a room to a single channel on one IRC network.
+type IRCBridge struct {
+	RoomID  string
+	Server  string
+	Channel string
+	Nick    string
+	UseTLS  bool
+
+	conn    *irc.Connection
+	inbound chan Message
+}
+
+// NewIRCBridge constructs an IRCBridge for roomID mirroring channel on
+// server under nick.
+func NewIRCBridge(roomID, server, channel, nick string, useTLS bool) *IRCBridge {
+	return &IRCBridge{
+		RoomID:  roomID,
+		Server:  server,
+		Channel: channel,
+		Nick:    nick,
+		UseTLS:  useTLS,
+		inbound: make(chan Message, 32),
+	}
+}
+
+// Start connects to the IRC server, joins Channel, and blocks relaying
+// inbound PRIVMSGs to Receive until ctx is cancelled.
+func (b *IRCBridge) Start(ctx context.Context) error {
+	conn := irc.IRC(b.Nick, b.Nick)
+	conn.UseTLS = b.UseTLS
+
+	conn.AddCallback("001", func(e *irc.Event) {
+		conn.Join(b.Channel)
+	})
+	conn.AddCallback("PRIVMSG", func(e *irc.Event) {
+		if len(e.Arguments) == 0 || e.Arguments[0] != b.Channel {
+			return
+		}
+		b.inbound <- Message{
+			Username:  e.Nick,
+			Content:   e.Message(),
+			RoomID:    b.RoomID,
+			Timestamp: time.Now(),
+		}
+	})
+
+	if err := conn.Connect(b.Server); err != nil {
+		return fmt.Errorf("connect to %s: %w", b.Server, err)
+	}
+	b.conn = conn
+
+	go func() {
+		<-ctx.Done()
+		conn.Quit()
+	}()
+
+	conn.Loop()
+	return ctx.Err()
+}
+
+// Send relays msg to Channel as "<username> content".
+func (b *IRCBridge) Send(msg Message) error {
+	if b.conn == nil {
+		return fmt.Errorf("irc bridge not started")
+	}
+	b.conn.Privmsg(b.Channel, fmt.Sprintf("<%s> %s", msg.Username, msg.Content))
+	return nil
+}
+
+func (b *IRCBridge) Receive() <-chan Message {
+	return b.inbound
+}
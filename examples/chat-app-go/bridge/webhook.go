@@ -0,0 +1,88 @@
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// WebhookBridge mirrors a room via a pair of plain HTTP webhooks: an
+// inbound endpoint the host exposes (fed through HandleInbound) and an
+// outbound POST fired on every local message, matching the Rocket.Chat
+// incoming/outgoing webhook shape.
+type WebhookBridge struct {
+	RoomID      string
+	OutboundURL string
+	HTTPClient  *http.Client
+
+	inbound chan Message
+}
+
+// NewWebhookBridge constructs a WebhookBridge for roomID that posts
+// outbound messages to outboundURL.
+func NewWebhookBridge(roomID, outboundURL string) *WebhookBridge {
+	return &WebhookBridge{
+		RoomID:      roomID,
+		OutboundURL: outboundURL,
+		HTTPClient:  &http.Client{Timeout: 5 * time.Second},
+		inbound:     make(chan Message, 32),
+	}
+}
+
+// Start is a no-op beyond waiting on ctx: the webhook bridge has nothing to
+// connect to, its inbound side is driven entirely by HandleInbound.
+func (b *WebhookBridge) Start(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// Send POSTs msg to OutboundURL as a Rocket.Chat-style webhook payload.
+func (b *WebhookBridge) Send(msg Message) error {
+	payload, err := json.Marshal(webhookPayload{
+		Username: msg.Username,
+		Text:     msg.Content,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal webhook payload: %w", err)
+	}
+
+	resp, err := b.HTTPClient.Post(b.OutboundURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+func (b *WebhookBridge) Receive() <-chan Message {
+	return b.inbound
+}
+
+// HandleInbound parses a webhook POST body delivered to
+// /bridge/:room/webhook and queues it for Receive.
+func (b *WebhookBridge) HandleInbound(body []byte) error {
+	var payload webhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return fmt.Errorf("unmarshal webhook payload: %w", err)
+	}
+
+	b.inbound <- Message{
+		Username:  payload.Username,
+		Content:   payload.Text,
+		RoomID:    b.RoomID,
+		Timestamp: time.Now(),
+	}
+	return nil
+}
+
+type webhookPayload struct {
+	Username string `json:"username"`
+	Text     string `json:"text"`
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// permissionAdmin gates commands to the configured admin user and to users
+// whose join token grants permissionOp.
+const permissionAdmin = "admin"
+
+// permissionOp is the join-token permission that grants a non-admin user
+// access to permissionAdmin commands, so room owners can delegate /kick
+// and /sudo without sharing the server's -a admin account.
+const permissionOp = "op"
+
+// Command is a single slash command. Permissions returns permissionAdmin
+// for admin-only commands, or "" for anything a registered user may run.
+type Command interface {
+	Name() string
+	Permissions() string
+	Execute(connID string, args []string) error
+}
+
+// CommandRegistry looks up a Command by name for handleCommand.
+type CommandRegistry struct {
+	server   *ChatServer
+	handlers map[string]Command
+}
+
+func newCommandRegistry(server *ChatServer) *CommandRegistry {
+	r := &CommandRegistry{server: server, handlers: make(map[string]Command)}
+	for _, cmd := range []Command{
+		&nickCommand{server},
+		&joinCommand{server},
+		&leaveCommand{server},
+		&listCommand{server},
+		&whoCommand{server},
+		&msgCommand{server},
+		&createCommand{server},
+		&kickCommand{server},
+		&sudoCommand{server},
+	} {
+		r.handlers[cmd.Name()] = cmd
+	}
+	return r
+}
+
+func (r *CommandRegistry) Lookup(name string) (Command, bool) {
+	cmd, ok := r.handlers[name]
+	return cmd, ok
+}
+
+// --- /nick <name> ---
+
+type nickCommand struct{ server *ChatServer }
+
+func (c *nickCommand) Name() string        { return "nick" }
+func (c *nickCommand) Permissions() string { return "" }
+func (c *nickCommand) Execute(connID string, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: /nick <name>")
+	}
+	return c.server.changeUsername(connID, args[0])
+}
+
+// --- /join <room> ---
+
+type joinCommand struct{ server *ChatServer }
+
+func (c *joinCommand) Name() string       { return "join" }
+func (c *joinCommand) Permissions() string { return "" }
+func (c *joinCommand) Execute(connID string, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: /join <room>")
+	}
+	return c.server.requestJoinRoom(connID, args[0])
+}
+
+// --- /leave ---
+
+type leaveCommand struct{ server *ChatServer }
+
+func (c *leaveCommand) Name() string        { return "leave" }
+func (c *leaveCommand) Permissions() string { return "" }
+func (c *leaveCommand) Execute(connID string, args []string) error {
+	return c.server.requestLeaveRoom(connID)
+}
+
+// --- /list ---
+
+type listCommand struct{ server *ChatServer }
+
+func (c *listCommand) Name() string        { return "list" }
+func (c *listCommand) Permissions() string { return "" }
+func (c *listCommand) Execute(connID string, args []string) error {
+	c.server.mutex.RLock()
+	names := make([]string, 0, len(c.server.rooms))
+	for _, room := range c.server.rooms {
+		names = append(names, room.ID)
+	}
+	c.server.mutex.RUnlock()
+
+	return c.server.sendToUser(connID, SystemMessageResponse{
+		Type:      MessageTypeSystemMsg,
+		Content:   "rooms: " + strings.Join(names, ", "),
+		Timestamp: time.Now(),
+	})
+}
+
+// --- /who ---
+
+type whoCommand struct{ server *ChatServer }
+
+func (c *whoCommand) Name() string        { return "who" }
+func (c *whoCommand) Permissions() string { return "" }
+func (c *whoCommand) Execute(connID string, args []string) error {
+	c.server.mutex.RLock()
+	user, exists := c.server.users[connID]
+	if !exists {
+		c.server.mutex.RUnlock()
+		return fmt.Errorf("user not registered")
+	}
+	var names []string
+	for _, userID := range c.server.roomUsers[user.CurrentRoom] {
+		if u, ok := c.server.users[userID]; ok {
+			names = append(names, u.Username)
+		}
+	}
+	c.server.mutex.RUnlock()
+
+	return c.server.sendToUser(connID, SystemMessageResponse{
+		Type:      MessageTypeSystemMsg,
+		Content:   "in " + user.CurrentRoom + ": " + strings.Join(names, ", "),
+		Timestamp: time.Now(),
+	})
+}
+
+// --- /msg <user> <text> ---
+
+type msgCommand struct{ server *ChatServer }
+
+func (c *msgCommand) Name() string        { return "msg" }
+func (c *msgCommand) Permissions() string { return "" }
+func (c *msgCommand) Execute(connID string, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: /msg <user> <text>")
+	}
+	return c.server.sendPrivateMessage(connID, args[0], strings.Join(args[1:], " "))
+}
+
+// --- /create <room> ---
+
+type createCommand struct{ server *ChatServer }
+
+func (c *createCommand) Name() string        { return "create" }
+func (c *createCommand) Permissions() string { return "" }
+func (c *createCommand) Execute(connID string, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: /create <room>")
+	}
+	return c.server.createRoom(connID, args[0])
+}
+
+// --- /kick <user> ---
+
+type kickCommand struct{ server *ChatServer }
+
+func (c *kickCommand) Name() string        { return "kick" }
+func (c *kickCommand) Permissions() string { return permissionAdmin }
+func (c *kickCommand) Execute(connID string, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: /kick <user>")
+	}
+	return c.server.kickUser(connID, args[0])
+}
+
+// --- /sudo <cmd> ---
+
+type sudoCommand struct{ server *ChatServer }
+
+func (c *sudoCommand) Name() string        { return "sudo" }
+func (c *sudoCommand) Permissions() string { return permissionAdmin }
+func (c *sudoCommand) Execute(connID string, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: /sudo <cmd> [args...]")
+	}
+	sub, ok := c.server.commands.Lookup(args[0])
+	if !ok {
+		return fmt.Errorf("unknown command: /%s", args[0])
+	}
+	return sub.Execute(connID, args[1:])
+}
+
+// changeUsername renames a registered user, rejecting names already in use.
+func (s *ChatServer) changeUsername(connID, username string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	user, exists := s.users[connID]
+	if !exists {
+		return fmt.Errorf("user not registered")
+	}
+	for id, other := range s.users {
+		if id != connID && other.Username == username {
+			return fmt.Errorf("username already taken")
+		}
+	}
+	user.Username = username
+	return nil
+}
+
+// joinRoom moves a user from their current room into roomID, creating the
+// roomUsers bucket if needed. The broadcast stays under the lock, same as
+// handleChatMessage, since broadcastToRoom reads s.roomUsers/s.connections
+// without locking itself.
+func (s *ChatServer) joinRoom(connID, roomID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	user, exists := s.users[connID]
+	if !exists {
+		return fmt.Errorf("user not registered")
+	}
+	if _, exists := s.rooms[roomID]; !exists {
+		return fmt.Errorf("room does not exist: %s", roomID)
+	}
+
+	s.removeUserFromRoom(connID, user.CurrentRoom)
+	s.addUserToRoom(connID, roomID)
+	user.CurrentRoom = roomID
+
+	s.broadcastToRoom(roomID, UserEventResponse{
+		Type:      MessageTypeUserJoined,
+		User:      *user,
+		RoomID:    roomID,
+		Timestamp: time.Now(),
+	})
+	return nil
+}
+
+// requestJoinRoom asks connID's owning actionLoop to move it into roomID,
+// so the room change for this connection happens on that connection's
+// single owning goroutine rather than whatever goroutine ran the command.
+func (s *ChatServer) requestJoinRoom(connID, roomID string) error {
+	s.mutex.RLock()
+	conn, exists := s.connections[connID]
+	s.mutex.RUnlock()
+	if !exists {
+		return fmt.Errorf("connection not found")
+	}
+	conn.actions.Send(joinRoomAction{roomID: roomID})
+	return nil
+}
+
+// requestLeaveRoom asks connID's owning actionLoop to return it to general.
+func (s *ChatServer) requestLeaveRoom(connID string) error {
+	s.mutex.RLock()
+	conn, exists := s.connections[connID]
+	s.mutex.RUnlock()
+	if !exists {
+		return fmt.Errorf("connection not found")
+	}
+	conn.actions.Send(leaveRoomAction{})
+	return nil
+}
+
+// createRoom registers a new public room owned by the requesting user.
+func (s *ChatServer) createRoom(connID, roomID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.rooms[roomID]; exists {
+		return fmt.Errorf("room already exists: %s", roomID)
+	}
+	user, exists := s.users[connID]
+	if !exists {
+		return fmt.Errorf("user not registered")
+	}
+
+	s.rooms[roomID] = &Room{
+		ID:        roomID,
+		Name:      roomID,
+		IsPublic:  true,
+		MaxUsers:  50,
+		CreatedBy: user.Username,
+	}
+	s.roomUsers[roomID] = make([]string, 0)
+	s.messageHistory[roomID] = make([]ChatMessage, 0)
+
+	if err := s.store.EnsureRoomTable(roomID); err != nil {
+		return fmt.Errorf("create room table: %w", err)
+	}
+	return nil
+}
+
+// kickUser disconnects the named user, closing their socket with a
+// KickError close frame so the client can tell a kick from a crash.
+func (s *ChatServer) kickUser(byConnID, username string) error {
+	s.mutex.RLock()
+	by := s.users[byConnID]
+	var target *Connection
+	for connID, user := range s.users {
+		if user.Username == username {
+			target = s.connections[connID]
+			break
+		}
+	}
+	s.mutex.RUnlock()
+
+	if target == nil {
+		return fmt.Errorf("user not found: %s", username)
+	}
+
+	_, closeFrame := errorToWSCloseMessage(&KickError{By: by.Username})
+	target.ws.WriteControl(websocket.CloseMessage, closeFrame, time.Now().Add(5*time.Second))
+	target.ws.Close()
+	return nil
+}
+
+// sendPrivateMessage delivers a /msg DM to toUsername and echoes a delivery
+// receipt back to the sender.
+func (s *ChatServer) sendPrivateMessage(connID, toUsername, content string) error {
+	s.mutex.RLock()
+	from, exists := s.users[connID]
+	if !exists {
+		s.mutex.RUnlock()
+		return fmt.Errorf("user not registered")
+	}
+
+	var toConnID string
+	for id, user := range s.users {
+		if user.Username == toUsername {
+			toConnID = id
+			break
+		}
+	}
+	s.mutex.RUnlock()
+
+	if toConnID == "" {
+		return fmt.Errorf("user not found: %s", toUsername)
+	}
+
+	now := time.Now()
+	if err := s.sendToUser(toConnID, PrivateMessageResponse{
+		Type:      MessageTypePrivate,
+		From:      from.Username,
+		To:        toUsername,
+		Content:   content,
+		Timestamp: now,
+	}); err != nil {
+		return err
+	}
+
+	// Delivery receipt back to the sender.
+	return s.sendToUser(connID, PrivateMessageResponse{
+		Type:      MessageTypePrivate,
+		From:      from.Username,
+		To:        toUsername,
+		Content:   content,
+		Timestamp: now,
+	})
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	_ "modernc.org/sqlite"
+)
+
+// logMsg is a unit of work for the async DB writer goroutine.
+type logMsg struct {
+	message ChatMessage
+}
+
+// Store persists each room's messages to SQLite, one table per room, so
+// history survives restarts. Room definitions and users are not stored: a
+// room added with /create is gone after a restart, though its message table
+// remains.
+type Store struct {
+	db     *sql.DB
+	logCh  chan logMsg
+	logger *logrus.Logger
+}
+
+// NewStore opens (or creates) the SQLite database at path and starts the
+// background writer goroutine that drains logCh.
+func NewStore(path string, logger *logrus.Logger) (*Store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite db: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("ping sqlite db: %w", err)
+	}
+
+	s := &Store{
+		db:     db,
+		logCh:  make(chan logMsg, 256),
+		logger: logger,
+	}
+
+	go s.writeLoop()
+	return s, nil
+}
+
+// EnsureRoomTable creates the per-room message table if it doesn't exist yet.
+func (s *Store) EnsureRoomTable(roomID string) error {
+	table := roomTableName(roomID)
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		id TEXT PRIMARY KEY,
+		username TEXT NOT NULL,
+		avatar TEXT,
+		content TEXT NOT NULL,
+		timestamp INTEGER NOT NULL
+	)`, table)
+	_, err := s.db.Exec(query)
+	return err
+}
+
+// LoadHistory hydrates the last n messages for a room, oldest first.
+func (s *Store) LoadHistory(roomID string, n int) ([]ChatMessage, error) {
+	table := roomTableName(roomID)
+	query := fmt.Sprintf(`SELECT id, username, avatar, content, timestamp FROM %s ORDER BY timestamp DESC LIMIT ?`, table)
+	rows, err := s.db.Query(query, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []ChatMessage
+	for rows.Next() {
+		var (
+			msg    ChatMessage
+			ts     int64
+			avatar string
+		)
+		if err := rows.Scan(&msg.ID, &msg.User.Username, &avatar, &msg.Content, &ts); err != nil {
+			return nil, err
+		}
+		msg.Type = "message"
+		msg.RoomID = roomID
+		msg.User.Avatar = avatar
+		msg.Timestamp = time.Unix(0, ts)
+		messages = append(messages, msg)
+	}
+
+	// Rows came back newest-first; reverse to chronological order.
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages, rows.Err()
+}
+
+// LoadHistoryBefore pages through older messages for /rooms/:id/history.
+func (s *Store) LoadHistoryBefore(roomID string, before time.Time, limit int) ([]ChatMessage, error) {
+	table := roomTableName(roomID)
+	query := fmt.Sprintf(`SELECT id, username, avatar, content, timestamp FROM %s WHERE timestamp < ? ORDER BY timestamp DESC LIMIT ?`, table)
+	rows, err := s.db.Query(query, before.UnixNano(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []ChatMessage
+	for rows.Next() {
+		var (
+			msg    ChatMessage
+			ts     int64
+			avatar string
+		)
+		if err := rows.Scan(&msg.ID, &msg.User.Username, &avatar, &msg.Content, &ts); err != nil {
+			return nil, err
+		}
+		msg.Type = "message"
+		msg.RoomID = roomID
+		msg.User.Avatar = avatar
+		msg.Timestamp = time.Unix(0, ts)
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
+
+// Enqueue schedules a message for asynchronous persistence. It never blocks
+// the caller on DB I/O; callers should treat this as fire-and-forget. If the
+// writer is backed up, the message is dropped rather than blocking the
+// broadcaster, but the drop is logged and counted so a diverging store isn't
+// silent.
+func (s *Store) Enqueue(msg ChatMessage) {
+	select {
+	case s.logCh <- logMsg{message: msg}:
+	default:
+		storeDroppedTotal.Inc()
+		s.logger.Warnf("persistence queue full, dropping message %s for room %s", msg.ID, msg.RoomID)
+	}
+}
+
+func (s *Store) writeLoop() {
+	for entry := range s.logCh {
+		if err := s.insertMessage(entry.message); err != nil {
+			s.logger.Errorf("failed to persist message %s: %v", entry.message.ID, err)
+		}
+	}
+}
+
+func (s *Store) insertMessage(msg ChatMessage) error {
+	table := roomTableName(msg.RoomID)
+	query := fmt.Sprintf(`INSERT OR IGNORE INTO %s (id, username, avatar, content, timestamp) VALUES (?, ?, ?, ?, ?)`, table)
+	_, err := s.db.Exec(query, msg.ID, msg.User.Username, msg.User.Avatar, msg.Content, msg.Timestamp.UnixNano())
+	return err
+}
+
+func (s *Store) Close() error {
+	close(s.logCh)
+	return s.db.Close()
+}
+
+// roomTableName maps a room ID to a safe per-room table name. It hex-encodes
+// the full ID rather than substituting invalid characters, so distinct room
+// IDs (e.g. "a-b" and "a_b") can never collide on the same table.
+func roomTableName(roomID string) string {
+	return "messages_" + hex.EncodeToString([]byte(roomID))
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gorilla/websocket"
+)
+
+// ProtocolError means the client sent a payload readPump could not parse or
+// that violated the wire protocol (bad JSON, missing required field, bad
+// token). It closes with websocket.CloseProtocolError.
+type ProtocolError struct {
+	Reason string
+}
+
+func (e *ProtocolError) Error() string { return e.Reason }
+
+// UserError is a well-formed request the server declined for ordinary
+// reasons (username taken, room doesn't exist, bad command usage). It
+// closes with websocket.CloseNormalClosure.
+type UserError struct {
+	Reason string
+}
+
+func (e *UserError) Error() string { return e.Reason }
+
+// KickError means an operator forcibly disconnected the user via /kick. It
+// closes with websocket.CloseNormalClosure so the client can distinguish a
+// deliberate kick from a crash, while still treating it as a normal close.
+type KickError struct {
+	By     string
+	Reason string
+}
+
+func (e *KickError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("kicked by %s", e.By)
+	}
+	return fmt.Sprintf("kicked by %s: %s", e.By, e.Reason)
+}
+
+// errorToWSCloseMessage maps a typed error to the WSMessage readPump should
+// send the client before closing, and to the close frame bytes for the
+// underlying websocket.Conn.
+func errorToWSCloseMessage(err error) (WSMessage, []byte) {
+	code := websocket.CloseInternalServerErr
+	text := "internal server error"
+
+	switch e := err.(type) {
+	case *ProtocolError:
+		code, text = websocket.CloseProtocolError, e.Reason
+	case *UserError:
+		code, text = websocket.CloseNormalClosure, e.Reason
+	case *KickError:
+		code, text = websocket.CloseNormalClosure, e.Error()
+	default:
+		if err != nil {
+			text = err.Error()
+		}
+	}
+
+	msg := WSMessage{Type: MessageTypeError, Content: text}
+	return msg, websocket.FormatCloseMessage(code, text)
+}
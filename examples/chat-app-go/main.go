@@ -1,10 +1,16 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,8 +18,20 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+
+	"github.com/tecnomanu/pampa/examples/chat-app-go/bridge"
+	"github.com/tecnomanu/pampa/examples/chat-app-go/token"
 )
 
+// bridgeUsernamePrefix tags messages injected from an external bridge so
+// they're visually distinct and, more importantly, so relayToBridges never
+// sends them back out to the system they came from.
+const bridgeUsernamePrefix = "bridge:"
+
+// historyWindow is how many messages per room are kept in memory for
+// instant access; anything older is paged in from SQLite on demand.
+const historyWindow = 100
+
 // Message types
 type MessageType string
 
@@ -28,6 +46,7 @@ const (
 	MessageTypeHistory      MessageType = "message_history"
 	MessageTypeError        MessageType = "error"
 	MessageTypeRegResult    MessageType = "registration_result"
+	MessageTypePrivate      MessageType = "private_message"
 )
 
 // User represents a connected user
@@ -37,6 +56,18 @@ type User struct {
 	Avatar      string    `json:"avatar"`
 	JoinedAt    time.Time `json:"joinedAt"`
 	CurrentRoom string    `json:"currentRoom"`
+	Permissions []string  `json:"permissions,omitempty"`
+}
+
+// hasPermission reports whether the user was granted perm by their join
+// token, e.g. "present", "op", or "record".
+func (u *User) hasPermission(perm string) bool {
+	for _, p := range u.Permissions {
+		if p == perm {
+			return true
+		}
+	}
+	return false
 }
 
 // Room represents a chat room
@@ -65,6 +96,18 @@ type WSMessage struct {
 	Username string      `json:"username,omitempty"`
 	Content  string      `json:"content,omitempty"`
 	RoomID   string      `json:"room_id,omitempty"`
+	Dest     string      `json:"dest,omitempty"` // target username for private messages
+	Token    string      `json:"token,omitempty"` // signed join token, required for MessageTypeRegister
+}
+
+// PrivateMessageResponse is delivered to both the sender (as a delivery
+// receipt) and the recipient of a /msg command.
+type PrivateMessageResponse struct {
+	Type      MessageType `json:"type"`
+	From      string      `json:"from"`
+	To        string      `json:"to"`
+	Content   string      `json:"content"`
+	Timestamp time.Time   `json:"timestamp"`
 }
 
 // Response structures
@@ -104,12 +147,16 @@ type ErrorResponse struct {
 	Message string      `json:"message"`
 }
 
-// Connection represents a WebSocket connection
+// Connection represents a WebSocket connection. send and actions are both
+// unbounded, goroutine-backed queues: send carries outbound frames for
+// writePump, and actions carries connAction values processed one at a time
+// by actionLoop, the single goroutine that owns this connection's state.
 type Connection struct {
-	ws     *websocket.Conn
-	send   chan []byte
-	server *ChatServer
-	userID string
+	ws      *websocket.Conn
+	send    *Channel[[]byte]
+	actions *Channel[connAction]
+	server  *ChatServer
+	userID  string
 }
 
 // ChatServer manages all connections and chat logic
@@ -124,6 +171,11 @@ type ChatServer struct {
 	broadcast      chan []byte
 	mutex          sync.RWMutex
 	logger         *logrus.Logger
+	store          *Store
+	commands       *CommandRegistry
+	adminUsername  string
+	tokenSecret    []byte
+	bridges        *bridge.Registry
 }
 
 var upgrader = websocket.Upgrader{
@@ -134,13 +186,22 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-// NewChatServer creates a new chat server instance
-func NewChatServer() *ChatServer {
+// NewChatServer creates a new chat server instance, opening dbPath as its
+// SQLite-backed message store and hydrating messageHistory from it.
+// adminUsername identifies the user allowed to run admin-only commands such
+// as /sudo and /kick (users whose token grants "op" may run them too), and
+// tokenSecret verifies the join tokens clients present on registration.
+func NewChatServer(dbPath, adminUsername string, tokenSecret []byte) (*ChatServer, error) {
 	logger := logrus.New()
 	logger.SetFormatter(&logrus.TextFormatter{
 		FullTimestamp: true,
 	})
 
+	store, err := NewStore(dbPath, logger)
+	if err != nil {
+		return nil, fmt.Errorf("open store: %w", err)
+	}
+
 	server := &ChatServer{
 		connections:    make(map[string]*Connection),
 		users:          make(map[string]*User),
@@ -151,10 +212,34 @@ func NewChatServer() *ChatServer {
 		unregister:     make(chan *Connection),
 		broadcast:      make(chan []byte),
 		logger:         logger,
+		store:          store,
+		adminUsername:  adminUsername,
+		tokenSecret:    tokenSecret,
 	}
+	server.commands = newCommandRegistry(server)
+	server.bridges = bridge.NewRegistry()
 
 	server.initializeDefaultRooms()
-	return server
+	if err := server.hydrateHistory(); err != nil {
+		return nil, fmt.Errorf("hydrate history: %w", err)
+	}
+	return server, nil
+}
+
+// hydrateHistory loads the last historyWindow messages of each known room
+// from the store so a server restart doesn't lose recent context.
+func (s *ChatServer) hydrateHistory() error {
+	for roomID := range s.rooms {
+		if err := s.store.EnsureRoomTable(roomID); err != nil {
+			return fmt.Errorf("room %s: %w", roomID, err)
+		}
+		messages, err := s.store.LoadHistory(roomID, historyWindow)
+		if err != nil {
+			return fmt.Errorf("room %s: %w", roomID, err)
+		}
+		s.messageHistory[roomID] = messages
+	}
+	return nil
 }
 
 func (s *ChatServer) initializeDefaultRooms() {
@@ -214,6 +299,7 @@ func (s *ChatServer) handleRegister(conn *Connection) {
 	connID := uuid.New().String()
 	conn.userID = connID
 	s.connections[connID] = conn
+	connectionsActive.Inc()
 	s.logger.Infof("New connection registered: %s", connID)
 }
 
@@ -229,46 +315,68 @@ func (s *ChatServer) handleUnregister(conn *Connection) {
 		}
 
 		delete(s.connections, conn.userID)
-		close(conn.send)
+		conn.actions.Send(closeAction{})
+		connectionsActive.Dec()
 		s.logger.Infof("Connection unregistered: %s", conn.userID)
 	}
 }
 
+// handleBroadcast hands the message off to every connection's own actions
+// queue instead of writing conn.send directly. Because actions and send are
+// both unbounded, this never blocks and never needs to close or evict a
+// connection on the broadcaster's behalf — that stays owned by
+// handleUnregister and each connection's actionLoop.
 func (s *ChatServer) handleBroadcast(message []byte) {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
 	for _, conn := range s.connections {
-		select {
-		case conn.send <- message:
-		default:
-			close(conn.send)
-			delete(s.connections, conn.userID)
-		}
+		conn.actions.Send(sendMessageAction{data: message})
 	}
 }
 
-func (s *ChatServer) registerUser(connID, username string) error {
+// registerUser verifies tok against s.tokenSecret, checks that its claims
+// match username, and registers the user with the permissions the token
+// grants. A missing or malformed token is a ProtocolError; an expired one
+// or a taken username is a UserError.
+func (s *ChatServer) registerUser(connID, username, tok string) error {
+	claims, err := token.Verify(s.tokenSecret, tok)
+	if err != nil {
+		return &ProtocolError{Reason: "invalid token: " + err.Error()}
+	}
+	if claims.Expired(time.Now()) {
+		return &UserError{Reason: "token expired"}
+	}
+	if claims.Username != username {
+		return &ProtocolError{Reason: "token username mismatch"}
+	}
+
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
 	// Check if username already exists
 	for _, user := range s.users {
 		if user.Username == username {
-			return fmt.Errorf("username already taken")
+			return &UserError{Reason: "username already taken"}
 		}
 	}
 
+	room := claims.Room
+	if _, exists := s.rooms[room]; !exists {
+		room = "general"
+	}
+
 	user := &User{
 		ID:          connID,
 		Username:    username,
 		Avatar:      s.generateAvatarColor(),
 		JoinedAt:    time.Now(),
-		CurrentRoom: "general",
+		CurrentRoom: room,
+		Permissions: claims.Permissions,
 	}
 
 	s.users[connID] = user
-	s.addUserToRoom(connID, "general")
+	s.addUserToRoom(connID, room)
 
 	s.logger.Infof("User registered: %s (%s)", username, connID)
 	return nil
@@ -299,6 +407,19 @@ func (s *ChatServer) removeUserFromRoom(userID, roomID string) {
 }
 
 func (s *ChatServer) handleChatMessage(connID, content string) error {
+	if len(content) == 0 {
+		return fmt.Errorf("message content cannot be empty")
+	}
+
+	// Commands dispatch through their own handlers, each of which takes
+	// s.mutex itself, so they must run outside this function's lock.
+	if content[0] == '/' {
+		return s.handleCommand(connID, content)
+	}
+
+	start := time.Now()
+	defer func() { messageHandlingSeconds.Observe(time.Since(start).Seconds()) }()
+
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
@@ -312,11 +433,6 @@ func (s *ChatServer) handleChatMessage(connID, content string) error {
 		return fmt.Errorf("user not in any room")
 	}
 
-	// Handle commands
-	if content[0] == '/' {
-		return s.handleCommand(connID, content)
-	}
-
 	message := ChatMessage{
 		ID:        uuid.New().String(),
 		Type:      "message",
@@ -329,11 +445,16 @@ func (s *ChatServer) handleChatMessage(connID, content string) error {
 	// Add to history
 	s.messageHistory[roomID] = append(s.messageHistory[roomID], message)
 
-	// Keep only last 100 messages
-	if len(s.messageHistory[roomID]) > 100 {
-		s.messageHistory[roomID] = s.messageHistory[roomID][len(s.messageHistory[roomID])-100:]
+	// Keep only last historyWindow messages in memory; older ones remain
+	// queryable via the store and the /rooms/:id/history endpoint.
+	if len(s.messageHistory[roomID]) > historyWindow {
+		s.messageHistory[roomID] = s.messageHistory[roomID][len(s.messageHistory[roomID])-historyWindow:]
 	}
 
+	// Persist asynchronously so DB I/O never blocks the broadcast.
+	s.store.Enqueue(message)
+	messagesTotal.WithLabelValues(roomID).Inc()
+
 	// Broadcast to room
 	response := NewMessageResponse{
 		Type:    MessageTypeNewMessage,
@@ -341,36 +462,146 @@ func (s *ChatServer) handleChatMessage(connID, content string) error {
 	}
 
 	s.broadcastToRoom(roomID, response)
+	s.relayToBridges(message)
 	return nil
 }
 
-func (s *ChatServer) handleCommand(connID, command string) error {
-	// Implementation of chat commands would go here
-	// For brevity, just send a system message
-	response := SystemMessageResponse{
-		Type:      MessageTypeSystemMsg,
-		Content:   "Commands not implemented in this demo",
+// relayToBridges fans a freshly stored message out to every bridge
+// registered for its room. Each Send runs in its own goroutine so a slow
+// or unreachable remote endpoint can never stall the broadcaster.
+func (s *ChatServer) relayToBridges(message ChatMessage) {
+	if strings.HasPrefix(message.User.Username, bridgeUsernamePrefix) {
+		return
+	}
+	for _, b := range s.bridges.For(message.RoomID) {
+		b := b
+		go func() {
+			if err := b.Send(bridge.Message{
+				ID:        message.ID,
+				Username:  message.User.Username,
+				Content:   message.Content,
+				RoomID:    message.RoomID,
+				Timestamp: message.Timestamp,
+			}); err != nil {
+				s.logger.Warnf("bridge relay failed for room %s: %v", message.RoomID, err)
+			}
+		}()
+	}
+}
+
+// injectExternalMessage appends a message an external bridge delivered into
+// the room's history and broadcasts it to local connections. The username
+// is tagged with bridgeUsernamePrefix so relayToBridges recognizes it and
+// doesn't send it straight back out, which would loop. The broadcast stays
+// under the lock, same as handleChatMessage, since broadcastToRoom reads
+// s.roomUsers/s.connections without locking itself.
+func (s *ChatServer) injectExternalMessage(roomID, username, content string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.rooms[roomID]; !exists {
+		s.logger.Warnf("dropping bridge message for unknown room %s", roomID)
+		return
+	}
+
+	message := ChatMessage{
+		ID:        uuid.New().String(),
+		Type:      "message",
+		User:      User{Username: bridgeUsernamePrefix + username},
+		Content:   content,
+		RoomID:    roomID,
 		Timestamp: time.Now(),
 	}
 
-	return s.sendToUser(connID, response)
+	s.messageHistory[roomID] = append(s.messageHistory[roomID], message)
+	if len(s.messageHistory[roomID]) > historyWindow {
+		s.messageHistory[roomID] = s.messageHistory[roomID][len(s.messageHistory[roomID])-historyWindow:]
+	}
+	s.store.Enqueue(message)
+
+	s.broadcastToRoom(roomID, NewMessageResponse{
+		Type:    MessageTypeNewMessage,
+		Message: message,
+	})
 }
 
-func (s *ChatServer) sendToUser(userID string, data interface{}) error {
-	if conn, exists := s.connections[userID]; exists {
-		jsonData, err := json.Marshal(data)
-		if err != nil {
-			return err
-		}
+// startBridges starts every registered bridge and relays whatever it
+// receives into the local room via injectExternalMessage. It returns
+// immediately; the bridges run until ctx is cancelled.
+func (s *ChatServer) startBridges(ctx context.Context) {
+	for _, b := range s.bridges.All() {
+		b := b
+		go func() {
+			if err := b.Start(ctx); err != nil && ctx.Err() == nil {
+				s.logger.Errorf("bridge stopped: %v", err)
+			}
+		}()
+		go func() {
+			for msg := range b.Receive() {
+				s.injectExternalMessage(msg.RoomID, msg.Username, msg.Content)
+			}
+		}()
+	}
+}
 
-		select {
-		case conn.send <- jsonData:
-			return nil
-		default:
-			return fmt.Errorf("failed to send message")
+// handleCommand parses a "/name arg1 arg2" line and dispatches it to the
+// registered Command. Unknown commands are reported back as ErrorResponse.
+func (s *ChatServer) handleCommand(connID, command string) error {
+	name, args := parseCommand(command)
+
+	cmd, ok := s.commands.Lookup(name)
+	if !ok {
+		return s.sendToUser(connID, ErrorResponse{
+			Type:    MessageTypeError,
+			Message: fmt.Sprintf("unknown command: /%s", name),
+		})
+	}
+
+	if cmd.Permissions() == permissionAdmin {
+		s.mutex.RLock()
+		user, exists := s.users[connID]
+		s.mutex.RUnlock()
+		isAdmin := exists && (user.Username == s.adminUsername || user.hasPermission(permissionOp))
+		if !isAdmin {
+			return s.sendToUser(connID, ErrorResponse{
+				Type:    MessageTypeError,
+				Message: "insufficient permissions",
+			})
 		}
 	}
-	return fmt.Errorf("user not found")
+
+	if err := cmd.Execute(connID, args); err != nil {
+		return s.sendToUser(connID, ErrorResponse{
+			Type:    MessageTypeError,
+			Message: err.Error(),
+		})
+	}
+	return nil
+}
+
+// parseCommand splits "/nick foo bar" into ("nick", ["foo", "bar"]).
+func parseCommand(content string) (string, []string) {
+	fields := strings.Fields(strings.TrimPrefix(content, "/"))
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return fields[0], fields[1:]
+}
+
+func (s *ChatServer) sendToUser(userID string, data interface{}) error {
+	s.mutex.RLock()
+	conn, exists := s.connections[userID]
+	s.mutex.RUnlock()
+	if !exists {
+		return fmt.Errorf("user not found")
+	}
+
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	conn.actions.Send(sendMessageAction{data: jsonData})
+	return nil
 }
 
 func (s *ChatServer) broadcastToRoom(roomID string, data interface{}) {
@@ -381,15 +612,14 @@ func (s *ChatServer) broadcastToRoom(roomID string, data interface{}) {
 	}
 
 	if users, exists := s.roomUsers[roomID]; exists {
+		sent := 0
 		for _, userID := range users {
 			if conn, exists := s.connections[userID]; exists {
-				select {
-				case conn.send <- jsonData:
-				default:
-					s.logger.Warnf("Failed to send to user %s", userID)
-				}
+				conn.actions.Send(sendMessageAction{data: jsonData})
+				sent++
 			}
 		}
+		roomBroadcastFanout.Observe(float64(sent))
 	}
 }
 
@@ -411,21 +641,36 @@ func (c *Connection) readPump() {
 		_, messageData, err := c.ws.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				wsErrorsTotal.WithLabelValues("unexpected_close").Inc()
 				c.server.logger.Error("WebSocket error:", err)
+			} else {
+				wsErrorsTotal.WithLabelValues("read").Inc()
 			}
-			break
+			return
 		}
 
 		var msg WSMessage
 		if err := json.Unmarshal(messageData, &msg); err != nil {
-			c.server.logger.Error("Failed to unmarshal message:", err)
-			continue
+			wsErrorsTotal.WithLabelValues("protocol").Inc()
+			c.closeWithError(&ProtocolError{Reason: "malformed message: " + err.Error()})
+			return
 		}
 
-		c.handleMessage(msg)
+		if err := c.handleMessage(msg); err != nil {
+			c.closeWithError(err)
+			return
+		}
 	}
 }
 
+// closeWithError maps err to a close frame via errorToWSCloseMessage and
+// sends it before the deferred c.ws.Close() tears down the connection.
+func (c *Connection) closeWithError(err error) {
+	wsMsg, closeFrame := errorToWSCloseMessage(err)
+	c.server.sendToUser(c.userID, wsMsg)
+	c.ws.WriteControl(websocket.CloseMessage, closeFrame, time.Now().Add(5*time.Second))
+}
+
 func (c *Connection) writePump() {
 	ticker := time.NewTicker(54 * time.Second)
 	defer func() {
@@ -435,7 +680,7 @@ func (c *Connection) writePump() {
 
 	for {
 		select {
-		case message, ok := <-c.send:
+		case message, ok := <-c.send.Out():
 			c.ws.SetWriteDeadline(time.Now().Add(10 * time.Second))
 			if !ok {
 				c.ws.WriteMessage(websocket.CloseMessage, []byte{})
@@ -443,52 +688,70 @@ func (c *Connection) writePump() {
 			}
 
 			if err := c.ws.WriteMessage(websocket.TextMessage, message); err != nil {
+				wsErrorsTotal.WithLabelValues("write").Inc()
 				return
 			}
 
 		case <-ticker.C:
 			c.ws.SetWriteDeadline(time.Now().Add(10 * time.Second))
 			if err := c.ws.WriteMessage(websocket.PingMessage, nil); err != nil {
+				wsErrorsTotal.WithLabelValues("ping").Inc()
 				return
 			}
 		}
 	}
 }
 
-func (c *Connection) handleMessage(msg WSMessage) {
+// handleMessage dispatches one decoded WSMessage. It returns a non-nil
+// error only for protocol-fatal conditions (e.g. a malformed or forged join
+// token); readPump closes the connection with the code that error maps to.
+// Ordinary failures (username taken, bad command usage) are reported back
+// to the client and do not end the connection.
+func (c *Connection) handleMessage(msg WSMessage) error {
 	switch msg.Type {
 	case MessageTypeRegister:
-		if err := c.server.registerUser(c.userID, msg.Username); err != nil {
+		err := c.server.registerUser(c.userID, msg.Username, msg.Token)
+		if err != nil {
+			if _, ok := err.(*ProtocolError); ok {
+				return err
+			}
 			response := RegistrationResponse{
 				Type:    MessageTypeRegResult,
 				Success: false,
 				Error:   err.Error(),
 			}
 			c.server.sendToUser(c.userID, response)
-		} else {
-			user := c.server.users[c.userID]
-			response := RegistrationResponse{
-				Type:    MessageTypeRegResult,
-				Success: true,
-				User:    user,
-			}
-			c.server.sendToUser(c.userID, response)
+			return nil
+		}
 
-			// Send message history
-			c.server.mutex.RLock()
-			history := c.server.messageHistory["general"]
-			c.server.mutex.RUnlock()
+		c.server.mutex.RLock()
+		user := c.server.users[c.userID]
+		room := user.CurrentRoom
+		history := c.server.messageHistory[room]
+		c.server.mutex.RUnlock()
 
-			historyResponse := MessageHistoryResponse{
-				Type:     MessageTypeHistory,
-				Messages: history,
-				RoomID:   "general",
-			}
-			c.server.sendToUser(c.userID, historyResponse)
+		response := RegistrationResponse{
+			Type:    MessageTypeRegResult,
+			Success: true,
+			User:    user,
 		}
+		c.server.sendToUser(c.userID, response)
+
+		historyResponse := MessageHistoryResponse{
+			Type:     MessageTypeHistory,
+			Messages: history,
+			RoomID:   room,
+		}
+		c.server.sendToUser(c.userID, historyResponse)
 
 	case MessageTypeMessage:
-		if err := c.server.handleChatMessage(c.userID, msg.Content); err != nil {
+		var err error
+		if msg.Dest != "" {
+			err = c.server.sendPrivateMessage(c.userID, msg.Dest, msg.Content)
+		} else {
+			err = c.server.handleChatMessage(c.userID, msg.Content)
+		}
+		if err != nil {
 			response := ErrorResponse{
 				Type:    MessageTypeError,
 				Message: err.Error(),
@@ -496,6 +759,79 @@ func (c *Connection) handleMessage(msg WSMessage) {
 			c.server.sendToUser(c.userID, response)
 		}
 	}
+	return nil
+}
+
+// handleBridgeWebhook serves POST /bridge/:room/webhook, the inbound side
+// of a WebhookBridge. wb is nil when no webhook bridge was configured.
+func handleBridgeWebhook(wb *bridge.WebhookBridge) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if wb == nil || c.Param("room") != wb.RoomID {
+			c.JSON(http.StatusNotFound, gin.H{"error": "no webhook bridge for this room"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			return
+		}
+
+		if err := wb.HandleInbound(body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	}
+}
+
+// handleRoomHistory serves GET /rooms/:id/history?before=<unix_nano>&limit=<n>
+// so clients can page through messages older than the in-memory window.
+func handleRoomHistory(server *ChatServer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roomID := c.Param("id")
+
+		server.mutex.RLock()
+		_, exists := server.rooms[roomID]
+		server.mutex.RUnlock()
+		if !exists {
+			c.JSON(http.StatusNotFound, gin.H{"error": "room not found"})
+			return
+		}
+
+		before := time.Now()
+		if raw := c.Query("before"); raw != "" {
+			nanos, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid before timestamp"})
+				return
+			}
+			before = time.Unix(0, nanos)
+		}
+
+		limit := 50
+		if raw := c.Query("limit"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+				return
+			}
+			limit = n
+		}
+
+		messages, err := server.store.LoadHistoryBefore(roomID, before, limit)
+		if err != nil {
+			server.logger.Error("Failed to load room history:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load history"})
+			return
+		}
+
+		c.JSON(http.StatusOK, MessageHistoryResponse{
+			Type:     MessageTypeHistory,
+			Messages: messages,
+			RoomID:   roomID,
+		})
+	}
 }
 
 func handleWebSocket(server *ChatServer) gin.HandlerFunc {
@@ -507,20 +843,75 @@ func handleWebSocket(server *ChatServer) gin.HandlerFunc {
 		}
 
 		conn := &Connection{
-			ws:     ws,
-			send:   make(chan []byte, 256),
-			server: server,
+			ws:      ws,
+			send:    NewChannel[[]byte](),
+			actions: NewChannel[connAction](),
+			server:  server,
 		}
 
 		server.register <- conn
 
+		go conn.actionLoop()
 		go conn.writePump()
 		go conn.readPump()
 	}
 }
 
+// loadTokenSecret reads the HMAC secret used to verify join tokens from
+// secretFile if given, otherwise from $PAMPA_TOKEN_SECRET.
+func loadTokenSecret(secretFile string) ([]byte, error) {
+	if secretFile != "" {
+		secret, err := os.ReadFile(secretFile)
+		if err != nil {
+			return nil, fmt.Errorf("read secret file: %w", err)
+		}
+		return []byte(strings.TrimSpace(string(secret))), nil
+	}
+	if secret := os.Getenv("PAMPA_TOKEN_SECRET"); secret != "" {
+		return []byte(secret), nil
+	}
+	return nil, fmt.Errorf("no token secret configured: set -token-secret-file or $PAMPA_TOKEN_SECRET")
+}
+
 func main() {
-	server := NewChatServer()
+	dbPath := flag.String("db", "pampa-chat.db", "path to the SQLite database file used for message history")
+	adminUsername := flag.String("a", "", "username granted admin-only commands such as /sudo and /kick")
+	secretFile := flag.String("token-secret-file", "", "path to the file holding the HMAC secret used to verify join tokens (falls back to $PAMPA_TOKEN_SECRET)")
+	webhookBridgeRoom := flag.String("webhook-bridge-room", "", "room ID to mirror via WebhookBridge")
+	webhookBridgeOutboundURL := flag.String("webhook-bridge-outbound-url", "", "URL to POST outbound messages for -webhook-bridge-room")
+	ircBridgeRoom := flag.String("irc-bridge-room", "", "room ID to mirror via IRCBridge")
+	ircBridgeServer := flag.String("irc-bridge-server", "", "IRC server address (host:port) for -irc-bridge-room")
+	ircBridgeChannel := flag.String("irc-bridge-channel", "", "IRC channel to mirror for -irc-bridge-room")
+	ircBridgeNick := flag.String("irc-bridge-nick", "pampa-bridge", "nick the IRC bridge connects as")
+	opsAddr := flag.String("ops-addr", ":9090", "address for the internal /metrics, /healthz, /readyz, /debug/rooms listener")
+	flag.Parse()
+
+	tokenSecret, err := loadTokenSecret(*secretFile)
+	if err != nil {
+		log.Fatalf("failed to load token secret: %v", err)
+	}
+
+	server, err := NewChatServer(*dbPath, *adminUsername, tokenSecret)
+	if err != nil {
+		log.Fatalf("failed to start chat server: %v", err)
+	}
+
+	var webhookBridge *bridge.WebhookBridge
+	if *webhookBridgeRoom != "" && *webhookBridgeOutboundURL != "" {
+		webhookBridge = bridge.NewWebhookBridge(*webhookBridgeRoom, *webhookBridgeOutboundURL)
+		server.bridges.Register(*webhookBridgeRoom, webhookBridge)
+	}
+	if *ircBridgeRoom != "" && *ircBridgeServer != "" && *ircBridgeChannel != "" {
+		ircBridge := bridge.NewIRCBridge(*ircBridgeRoom, *ircBridgeServer, *ircBridgeChannel, *ircBridgeNick, false)
+		server.bridges.Register(*ircBridgeRoom, ircBridge)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server.startBridges(ctx)
+
+	startOpsServer(*opsAddr, server)
+
 	go server.run()
 
 	r := gin.Default()
@@ -531,6 +922,12 @@ func main() {
 	// WebSocket endpoint
 	r.GET("/ws", handleWebSocket(server))
 
+	// Paginated room history
+	r.GET("/rooms/:id/history", handleRoomHistory(server))
+
+	// Inbound webhook bridge endpoint
+	r.POST("/bridge/:room/webhook", handleBridgeWebhook(webhookBridge))
+
 	// Main page
 	r.GET("/", func(c *gin.Context) {
 		c.File("./static/index.html")
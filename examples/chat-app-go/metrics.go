@@ -0,0 +1,40 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	messagesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pampa_messages_total",
+		Help: "Total chat messages handled, by room.",
+	}, []string{"room"})
+
+	connectionsActive = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "pampa_connections_active",
+		Help: "Currently open WebSocket connections.",
+	})
+
+	wsErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pampa_ws_errors_total",
+		Help: "WebSocket errors, by kind (read, write, ping, protocol, unexpected_close).",
+	}, []string{"kind"})
+
+	messageHandlingSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "pampa_message_handling_seconds",
+		Help:    "Time to handle and broadcast a single chat message.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	roomBroadcastFanout = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "pampa_room_broadcast_fanout",
+		Help:    "Number of connections a single room broadcast was sent to.",
+		Buckets: []float64{1, 2, 5, 10, 25, 50, 100, 250},
+	})
+
+	storeDroppedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "pampa_store_dropped_messages_total",
+		Help: "Messages dropped before persistence because the writer queue was full.",
+	})
+)
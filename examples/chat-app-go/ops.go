@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// startOpsServer serves /metrics, /healthz, /readyz, and /debug/rooms on
+// addr, a separate listener from the public-facing :8082 server so these
+// aren't exposed alongside it.
+func startOpsServer(addr string, server *ChatServer) {
+	ops := gin.New()
+	ops.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	ops.GET("/healthz", handleHealthz)
+	ops.GET("/readyz", handleReadyz(server))
+	ops.GET("/debug/rooms", handleDebugRooms(server))
+
+	go func() {
+		if err := http.ListenAndServe(addr, ops); err != nil {
+			server.logger.Errorf("ops server stopped: %v", err)
+		}
+	}()
+}
+
+func handleHealthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// handleReadyz reports ready only once the store's DB connection is alive,
+// so a load balancer won't route traffic to a server that can't persist.
+func handleReadyz(server *ChatServer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := server.store.db.Ping(); err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "ready"})
+	}
+}
+
+type roomDebugInfo struct {
+	ID          string `json:"id"`
+	UserCount   int    `json:"userCount"`
+	HistorySize int    `json:"historySize"`
+}
+
+// handleDebugRooms returns per-room user counts and in-memory history
+// sizes for live inspection.
+func handleDebugRooms(server *ChatServer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		server.mutex.RLock()
+		rooms := make([]roomDebugInfo, 0, len(server.rooms))
+		for id := range server.rooms {
+			rooms = append(rooms, roomDebugInfo{
+				ID:          id,
+				UserCount:   len(server.roomUsers[id]),
+				HistorySize: len(server.messageHistory[id]),
+			})
+		}
+		server.mutex.RUnlock()
+
+		c.JSON(http.StatusOK, gin.H{"rooms": rooms})
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+// Channel is a goroutine-backed, unbounded FIFO queue. Unlike a plain
+// buffered Go channel, Send never blocks waiting for room: it only waits for
+// the internal goroutine to accept it into its backlog, which never backs up
+// on a slow or dead reader. This is what lets broadcasters hand off to every
+// connection without racing on a bounded `send chan []byte` or needing a
+// `default: drop` escape hatch.
+//
+// Close is safe to race against concurrent Sends: it never closes `in`
+// itself (which would panic a goroutine blocked sending on it) and instead
+// signals `done`, which Send selects against. A Send that loses that race
+// after Close silently drops its value rather than panicking or blocking
+// forever.
+type Channel[T any] struct {
+	in   chan T
+	out  chan T
+	done chan struct{}
+}
+
+// NewChannel creates a Channel and starts its backing goroutine.
+func NewChannel[T any]() *Channel[T] {
+	c := &Channel[T]{
+		in:   make(chan T),
+		out:  make(chan T),
+		done: make(chan struct{}),
+	}
+	go c.run()
+	return c
+}
+
+func (c *Channel[T]) run() {
+	var queue []T
+	for {
+		if len(queue) == 0 {
+			select {
+			case v := <-c.in:
+				queue = append(queue, v)
+			case <-c.done:
+				close(c.out)
+				return
+			}
+			continue
+		}
+
+		select {
+		case v := <-c.in:
+			queue = append(queue, v)
+		case c.out <- queue[0]:
+			queue = queue[1:]
+		case <-c.done:
+			close(c.out)
+			return
+		}
+	}
+}
+
+// Send enqueues v. It blocks only until the backing goroutine accepts it,
+// never on downstream readers. If Close has already been called, Send drops
+// v instead of panicking or blocking forever.
+func (c *Channel[T]) Send(v T) {
+	select {
+	case c.in <- v:
+	case <-c.done:
+	}
+}
+
+// Out returns the channel consumers should range/receive over. It is closed
+// once Close has been called.
+func (c *Channel[T]) Out() <-chan T {
+	return c.out
+}
+
+// Close stops the channel. Any backlog still queued at the moment Close is
+// called is discarded rather than drained, since by the time a connection's
+// actionLoop calls this it's already tearing down.
+func (c *Channel[T]) Close() {
+	close(c.done)
+}